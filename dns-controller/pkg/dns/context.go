@@ -0,0 +1,44 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+// Context is implemented by the dns-controller runtime. Watchers call
+// CreateScope once, at startup, to get a Scope they own exclusively.
+type Context interface {
+	CreateScope(name string) (Scope, error)
+}
+
+// Scope lets a single watcher publish the records it owns for a given
+// object, without clobbering records published by other watchers/scopes.
+type Scope interface {
+	// Replace sets the complete set of records for recordName, replacing
+	// any records previously published for it. A nil records slice removes
+	// the entry (used when the watched object is deleted).
+	Replace(recordName string, records []Record)
+
+	// MarkReady signals that this scope has completed its initial sync.
+	MarkReady()
+}
+
+// EnsureDotSuffix ensures a domain name ends with a trailing dot, as Route53
+// (and DNS generally) expects for fully-qualified names.
+func EnsureDotSuffix(s string) string {
+	if len(s) == 0 || s[len(s)-1] != '.' {
+		return s + "."
+	}
+	return s
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+// RecordType is the DNS record type of a Record.
+type RecordType string
+
+const (
+	RecordTypeA     RecordType = "A"
+	RecordTypeCNAME RecordType = "CNAME"
+	// RecordTypeAlias is not a real DNS record type; it represents a
+	// provider-specific alias (e.g. a Route53 ALIAS resource record) that
+	// points at another AWS resource's hosted zone rather than a
+	// CNAME/A value. Zone-apex names cannot hold a CNAME, so this is the
+	// only way to point an apex domain at an ELB/NLB/CloudFront hostname.
+	RecordTypeAlias RecordType = "ALIAS"
+)
+
+// Record is a single DNS record to be published for a watched object.
+type Record struct {
+	FQDN       string
+	RecordType RecordType
+	Value      string
+
+	// AliasTargetHostedZoneId and AliasDNSName are only set when
+	// RecordType is RecordTypeAlias; they identify the AWS resource
+	// (ELB/NLB/CloudFront) the alias should point to.
+	AliasTargetHostedZoneId string
+	AliasDNSName            string
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// route53DefaultTTL is used for plain value records (A/CNAME). ALIAS
+// records have no TTL of their own; Route53 uses the target's.
+const route53DefaultTTL = 60
+
+// Route53Change builds the route53.Change needed to apply action (typically
+// route53.ChangeActionUpsert or route53.ChangeActionDelete) for record.
+//
+// RecordTypeAlias records are translated into a Route53 ALIAS resource
+// record set (an "A" record carrying an AliasTarget instead of
+// ResourceRecords); everything else is translated as a plain value record.
+func Route53Change(action string, record Record) (*route53.Change, error) {
+	rrs := &route53.ResourceRecordSet{
+		Name: aws.String(record.FQDN),
+	}
+
+	switch record.RecordType {
+	case RecordTypeAlias:
+		if record.AliasDNSName == "" || record.AliasTargetHostedZoneId == "" {
+			return nil, fmt.Errorf("alias record for %q is missing AliasDNSName/AliasTargetHostedZoneId", record.FQDN)
+		}
+		// Route53 ALIAS records are not a distinct record type on the wire;
+		// they are an A (or AAAA) record set with an AliasTarget in place of
+		// ResourceRecords.
+		rrs.Type = aws.String(string(RecordTypeA))
+		rrs.AliasTarget = &route53.AliasTarget{
+			DNSName:              aws.String(EnsureDotSuffix(record.AliasDNSName)),
+			HostedZoneId:         aws.String(record.AliasTargetHostedZoneId),
+			EvaluateTargetHealth: aws.Bool(false),
+		}
+
+	case RecordTypeA, RecordTypeCNAME:
+		rrs.Type = aws.String(string(record.RecordType))
+		rrs.TTL = aws.Int64(route53DefaultTTL)
+		rrs.ResourceRecords = []*route53.ResourceRecord{
+			{Value: aws.String(record.Value)},
+		}
+
+	default:
+		return nil, fmt.Errorf("unhandled record type %q for %q", record.RecordType, record.FQDN)
+	}
+
+	return &route53.Change{
+		Action:            aws.String(action),
+		ResourceRecordSet: rrs,
+	}, nil
+}
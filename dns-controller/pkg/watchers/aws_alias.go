@@ -0,0 +1,148 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// cloudFrontHostedZoneId is the single, fixed hosted zone ID Route53 uses
+// for every CloudFront distribution, regardless of region.
+const cloudFrontHostedZoneId = "Z2FDTNDATAQYW2"
+
+// elbHostnameRegexp matches classic ELB and ALB hostnames, e.g.
+// "my-elb-1234567890.us-east-1.elb.amazonaws.com" (region in group 1), and
+// NLB hostnames, e.g. "my-nlb-1234567890abcdef.elb.us-east-1.amazonaws.com"
+// (region in group 2).
+var elbHostnameRegexp = regexp.MustCompile(`(?:\.([a-z0-9-]+)\.elb\.amazonaws\.com|\.elb\.([a-z0-9-]+)\.amazonaws\.com)\.?$`)
+
+// awsAliasResolver looks up the Route53 hosted zone ID to use for a
+// RecordTypeAlias record pointing at an ELB/NLB/CloudFront hostname. It
+// creates AWS clients lazily, on the first ELB/ALB/NLB hostname it resolves,
+// and keeps only the clients of the most recently used region.
+type awsAliasResolver struct {
+	mutex sync.Mutex
+	elb   *elb.ELB
+	elbv2 *elbv2.ELBV2
+	region string
+}
+
+func (r *awsAliasResolver) clientsForRegion(region string) (*elb.ELB, *elbv2.ELBV2, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.elb != nil && r.region == region {
+		return r.elb, r.elbv2, nil
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error building AWS session for region %q: %v", region, err)
+	}
+
+	r.region = region
+	r.elb = elb.New(sess)
+	r.elbv2 = elbv2.New(sess)
+
+	return r.elb, r.elbv2, nil
+}
+
+// aliasTargetForHostname classifies hostname (as published in an Ingress's
+// Status.LoadBalancer.Ingress) and, if it is an AWS resource that Route53
+// can alias to (a classic ELB, an ALB, an NLB, or a CloudFront distribution),
+// returns the hosted zone ID to use for a RecordTypeAlias record. ok is
+// false for anything else (e.g. a non-AWS hostname), and the caller should
+// fall back to a CNAME.
+//
+// Classic ELB, ALB and NLB hostnames are indistinguishable by regex alone
+// (they all match elbHostnameRegexp), and AWS publishes a different
+// canonical hosted zone ID per region for classic ELB/ALB than it does for
+// NLB, so there is no static table we can consult without risking handing
+// out the wrong zone ID. We always resolve these live via
+// findHostedZoneIdByDNSName, which disambiguates by actually querying both
+// the elb and elbv2 APIs.
+func (r *awsAliasResolver) aliasTargetForHostname(hostname string) (hostedZoneId string, ok bool) {
+	if strings.HasSuffix(hostname, ".cloudfront.net") || strings.HasSuffix(hostname, ".cloudfront.net.") {
+		return cloudFrontHostedZoneId, true
+	}
+
+	m := elbHostnameRegexp.FindStringSubmatch(hostname)
+	if m == nil {
+		return "", false
+	}
+	region := m[1]
+	if region == "" {
+		region = m[2]
+	}
+
+	zoneId, err := r.findHostedZoneIdByDNSName(region, hostname)
+	if err != nil || zoneId == "" {
+		return "", false
+	}
+	return zoneId, true
+}
+
+// findHostedZoneIdByDNSName describes the load balancers in region and
+// matches by DNS name, trying classic ELB first and then elbv2 (NLB/ALB),
+// so that it returns the correct canonical hosted zone ID regardless of
+// which load balancer type published hostname.
+func (r *awsAliasResolver) findHostedZoneIdByDNSName(region string, hostname string) (string, error) {
+	elbClient, elbv2Client, err := r.clientsForRegion(region)
+	if err != nil {
+		return "", err
+	}
+
+	var found string
+	err = elbClient.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(p *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range p.LoadBalancerDescriptions {
+			if strings.EqualFold(aws.StringValue(lb.DNSName), hostname) {
+				found = aws.StringValue(lb.CanonicalHostedZoneNameID)
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing classic ELBs: %v", err)
+	}
+	if found != "" {
+		return found, nil
+	}
+
+	err = elbv2Client.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(p *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range p.LoadBalancers {
+			if strings.EqualFold(aws.StringValue(lb.DNSName), hostname) {
+				found = aws.StringValue(lb.CanonicalHostedZoneId)
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing NLBs/ALBs: %v", err)
+	}
+
+	return found, nil
+}
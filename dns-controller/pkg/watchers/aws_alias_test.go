@@ -0,0 +1,47 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchers
+
+import "testing"
+
+// TestAliasTargetForHostnameNonAWS covers the classification paths that
+// don't require a live AWS call: CloudFront hostnames resolve to the fixed
+// zone ID, and hostnames that don't look like an AWS load balancer or
+// CloudFront distribution are rejected so the caller falls back to a CNAME.
+// The ELB/ALB/NLB branch always hits DescribeLoadBalancers(Pages) live (see
+// aliasTargetForHostname) and isn't covered here.
+func TestAliasTargetForHostnameNonAWS(t *testing.T) {
+	r := &awsAliasResolver{}
+
+	grid := []struct {
+		hostname   string
+		wantZoneId string
+		wantOK     bool
+	}{
+		{"d111111abcdef8.cloudfront.net", cloudFrontHostedZoneId, true},
+		{"d111111abcdef8.cloudfront.net.", cloudFrontHostedZoneId, true},
+		{"www.example.com", "", false},
+		{"my-service.default.svc.cluster.local", "", false},
+	}
+
+	for _, g := range grid {
+		zoneId, ok := r.aliasTargetForHostname(g.hostname)
+		if ok != g.wantOK || zoneId != g.wantZoneId {
+			t.Errorf("aliasTargetForHostname(%q) = (%q, %v), want (%q, %v)", g.hostname, zoneId, ok, g.wantZoneId, g.wantOK)
+		}
+	}
+}
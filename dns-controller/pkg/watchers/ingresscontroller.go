@@ -27,30 +27,79 @@ import (
 	"k8s.io/kubernetes/pkg/api/v1"
 	"k8s.io/kubernetes/pkg/apis/extensions/v1beta1"
 	client_extensions "k8s.io/kubernetes/pkg/client/clientset_generated/clientset/typed/extensions/v1beta1"
+	"k8s.io/kubernetes/pkg/labels"
 	"k8s.io/kubernetes/pkg/watch"
 )
 
+// IngressClassAnnotation is the de-facto standard annotation used by
+// multiple ingress controllers (nginx-ingress, traefik, alb-ingress) to
+// indicate which controller should act on an Ingress.
+const IngressClassAnnotation = "kubernetes.io/ingress.class"
+
+// IngressDNSIgnoreAnnotation opts a single Ingress out of dns-controller,
+// regardless of the configured ingressClass/labelSelector, so a user can
+// keep specific ingresses out of Route53 without changing global config.
+const IngressDNSIgnoreAnnotation = "dns.alpha.kubernetes.io/ignore"
+
 // IngressController watches for Ingress objects with dns labels
 type IngressController struct {
 	util.Stoppable
 	kubeClient client_extensions.ExtensionsV1beta1Interface
 	scope      dns.Scope
+	aliases    awsAliasResolver
+
+	// ingressClass, if set, restricts us to Ingresses whose
+	// IngressClassAnnotation equals this value, so we don't fight another
+	// ingress controller (nginx, traefik, alb-ingress) sharing the cluster.
+	ingressClass string
+
+	// labelSelector, if set, restricts us to Ingresses matching this label
+	// selector (e.g. "dns=kops").
+	labelSelector labels.Selector
 }
 
-// newIngressController creates a ingressController
-func NewIngressController(kubeClient client_extensions.ExtensionsV1beta1Interface, dns dns.Context) (*IngressController, error) {
+// NewIngressController creates an IngressController. ingressClass and
+// labelSelector may both be empty, in which case every Ingress in the
+// cluster that is not opted out via IngressDNSIgnoreAnnotation is considered.
+func NewIngressController(kubeClient client_extensions.ExtensionsV1beta1Interface, dns dns.Context, ingressClass string, labelSelector string) (*IngressController, error) {
 	scope, err := dns.CreateScope("ingress")
 	if err != nil {
 		return nil, fmt.Errorf("error building dns scope: %v", err)
 	}
+
+	selector := labels.Everything()
+	if labelSelector != "" {
+		selector, err = labels.Parse(labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ingress label selector %q: %v", labelSelector, err)
+		}
+	}
+
 	c := &IngressController{
-		kubeClient: kubeClient,
-		scope:      scope,
+		kubeClient:    kubeClient,
+		scope:         scope,
+		ingressClass:  ingressClass,
+		labelSelector: selector,
 	}
 
 	return c, nil
 }
 
+// shouldWatch reports whether ingress should be considered by this
+// controller: it isn't opted out via IngressDNSIgnoreAnnotation, and its
+// IngressClassAnnotation (if any) matches our configured ingressClass.
+func (c *IngressController) shouldWatch(ingress *v1beta1.Ingress) bool {
+	if ingress.Annotations[IngressDNSIgnoreAnnotation] != "" {
+		return false
+	}
+
+	if c.ingressClass != "" && ingress.Annotations[IngressClassAnnotation] != c.ingressClass {
+		return false
+	}
+
+	return true
+}
+
 // Run starts the IngressController.
 func (c *IngressController) Run() {
 	glog.Infof("starting ingress controller")
@@ -65,8 +114,7 @@ func (c *IngressController) Run() {
 func (c *IngressController) runWatcher(stopCh <-chan struct{}) {
 	runOnce := func() (bool, error) {
 		var listOpts v1.ListOptions
-		glog.Warningf("querying without label filter")
-		//listOpts.LabelSelector = labels.Everything()
+		listOpts.LabelSelector = c.labelSelector.String()
 		glog.Warningf("querying without field filter")
 		//listOpts.FieldSelector = fields.Everything()
 		ingressList, err := c.kubeClient.Ingresses("").List(listOpts)
@@ -76,12 +124,14 @@ func (c *IngressController) runWatcher(stopCh <-chan struct{}) {
 		for i := range ingressList.Items {
 			ingress := &ingressList.Items[i]
 			glog.V(4).Infof("found ingress: %v", ingress.Name)
+			if !c.shouldWatch(ingress) {
+				continue
+			}
 			c.updateIngressRecords(ingress)
 		}
 		c.scope.MarkReady()
 
-		glog.Warningf("querying without label filter")
-		//listOpts.LabelSelector = labels.Everything()
+		listOpts.LabelSelector = c.labelSelector.String()
 		glog.Warningf("querying without field filter")
 		//listOpts.FieldSelector = fields.Everything()
 		listOpts.Watch = true
@@ -107,6 +157,10 @@ func (c *IngressController) runWatcher(stopCh <-chan struct{}) {
 
 				switch event.Type {
 				case watch.Added, watch.Modified:
+					if !c.shouldWatch(ingress) {
+						c.scope.Replace(ingress.Name, nil)
+						continue
+					}
 					c.updateIngressRecords(ingress)
 
 				case watch.Deleted:
@@ -137,7 +191,7 @@ func preferCNAMEs(records []dns.Record) []dns.Record {
 	var as []dns.Record
 
 	for _, record := range records {
-		if record.RecordType == dns.RecordTypeCNAME {
+		if record.RecordType == dns.RecordTypeCNAME || record.RecordType == dns.RecordTypeAlias {
 			cnames = append(cnames, record)
 		} else if record.RecordType == dns.RecordTypeA {
 			as = append(as, record)
@@ -158,11 +212,18 @@ func (c *IngressController) updateIngressRecords(ingress *v1beta1.Ingress) {
 	for i := range ingress.Status.LoadBalancer.Ingress {
 		ingress := &ingress.Status.LoadBalancer.Ingress[i]
 		if ingress.Hostname != "" {
-			// TODO: Support ELB aliases
-			ingresses = append(ingresses, dns.Record{
-				RecordType: dns.RecordTypeCNAME,
-				Value:      ingress.Hostname,
-			})
+			if hostedZoneId, ok := c.aliases.aliasTargetForHostname(ingress.Hostname); ok {
+				ingresses = append(ingresses, dns.Record{
+					RecordType:              dns.RecordTypeAlias,
+					AliasDNSName:            ingress.Hostname,
+					AliasTargetHostedZoneId: hostedZoneId,
+				})
+			} else {
+				ingresses = append(ingresses, dns.Record{
+					RecordType: dns.RecordTypeCNAME,
+					Value:      ingress.Hostname,
+				})
+			}
 		}
 		if ingress.IP != "" {
 			ingresses = append(ingresses, dns.Record{
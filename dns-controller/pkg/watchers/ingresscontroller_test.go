@@ -0,0 +1,86 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchers
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/api/v1"
+	"k8s.io/kubernetes/pkg/apis/extensions/v1beta1"
+)
+
+func ingressWithAnnotations(annotations map[string]string) *v1beta1.Ingress {
+	return &v1beta1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "my-ingress",
+			Annotations: annotations,
+		},
+	}
+}
+
+func TestIngressControllerShouldWatch(t *testing.T) {
+	grid := []struct {
+		name         string
+		ingressClass string
+		annotations  map[string]string
+		want         bool
+	}{
+		{
+			name: "no ingress-class configured, no annotations",
+			want: true,
+		},
+		{
+			name:         "ingress-class configured, matching annotation",
+			ingressClass: "kops",
+			annotations:  map[string]string{IngressClassAnnotation: "kops"},
+			want:         true,
+		},
+		{
+			name:         "ingress-class configured, mismatched annotation",
+			ingressClass: "kops",
+			annotations:  map[string]string{IngressClassAnnotation: "nginx"},
+			want:         false,
+		},
+		{
+			name:         "ingress-class configured, annotation absent",
+			ingressClass: "kops",
+			want:         false,
+		},
+		{
+			name:        "no ingress-class configured, ignore annotation set",
+			annotations: map[string]string{IngressDNSIgnoreAnnotation: "true"},
+			want:        false,
+		},
+		{
+			name:         "ignore annotation wins even when ingress-class matches",
+			ingressClass: "kops",
+			annotations: map[string]string{
+				IngressClassAnnotation:     "kops",
+				IngressDNSIgnoreAnnotation: "true",
+			},
+			want: false,
+		},
+	}
+
+	for _, g := range grid {
+		c := &IngressController{ingressClass: g.ingressClass}
+		got := c.shouldWatch(ingressWithAnnotations(g.annotations))
+		if got != g.want {
+			t.Errorf("%s: shouldWatch() = %v, want %v", g.name, got, g.want)
+		}
+	}
+}
@@ -0,0 +1,611 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/golang/glog"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
+)
+
+// ApplicationLoadBalancer is an elbv2 "application" LoadBalancer: an L7 HTTP
+// option alongside the L4-only classic ELB/NLB LoadBalancer task. Unlike
+// LoadBalancer, routing is HTTP-aware: each listener carries a list of rules
+// (host/path -> TargetGroup), built from an Ingress's Spec.Rules by the
+// model builder that assembles this task.
+//go:generate fitask -type=ApplicationLoadBalancer
+type ApplicationLoadBalancer struct {
+	Name *string
+
+	// ARN is the load balancer's ARN, assigned by AWS on creation
+	ARN *string
+
+	DNSName      *string
+	HostedZoneId *string
+
+	Subnets        []*Subnet
+	SecurityGroups []*SecurityGroup
+
+	Listeners map[string]*ApplicationLoadBalancerListener
+}
+
+// ApplicationLoadBalancerListener is a single HTTP/HTTPS listener on an
+// ApplicationLoadBalancer, keyed (in the owning map) by port.
+type ApplicationLoadBalancerListener struct {
+	// ARN is the listener's ARN, populated by Find for an already-created
+	// listener so RenderAWS can reconcile it with ModifyListener/
+	// CreateRule/ModifyRule/DeleteRule instead of retrying CreateListener.
+	ARN *string
+
+	Protocol *string
+
+	// SSLCertificateID is the ACM ARN to present for an HTTPS listener.
+	SSLCertificateID *string
+
+	// DefaultTargetGroup receives traffic that matches none of Rules.
+	DefaultTargetGroup *TargetGroup
+
+	Rules []*ApplicationLoadBalancerRule
+}
+
+// ApplicationLoadBalancerRule forwards requests matching Host/Path to
+// TargetGroup. Priority must be unique within a listener; lower values are
+// evaluated first, mirroring elbv2 listener rule semantics.
+type ApplicationLoadBalancerRule struct {
+	// ARN is the rule's ARN, populated by Find for an already-created rule.
+	ARN *string
+
+	Priority    *int64
+	Host        *string
+	Path        *string
+	TargetGroup *TargetGroup
+}
+
+var _ fi.CompareWithID = &ApplicationLoadBalancer{}
+
+func (e *ApplicationLoadBalancer) CompareWithID() *string {
+	return e.ARN
+}
+
+func findApplicationLoadBalancer(cloud awsup.AWSCloud, name string) (*elbv2.LoadBalancer, error) {
+	request := &elbv2.DescribeLoadBalancersInput{
+		Names: []*string{&name},
+	}
+
+	var found []*elbv2.LoadBalancer
+	err := cloud.ELBV2().DescribeLoadBalancersPages(request, func(p *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range p.LoadBalancers {
+			if aws.StringValue(lb.LoadBalancerName) == name {
+				found = append(found, lb)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		if awsError, ok := err.(awserr.Error); ok {
+			if awsError.Code() == elbv2.ErrCodeLoadBalancerNotFoundException {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("error listing ALBs: %v", err)
+	}
+
+	if len(found) == 0 {
+		return nil, nil
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("found multiple ALBs with name %q", name)
+	}
+	return found[0], nil
+}
+
+func (e *ApplicationLoadBalancer) Find(c *fi.Context) (*ApplicationLoadBalancer, error) {
+	cloud := c.Cloud.(awsup.AWSCloud)
+
+	lb, err := findApplicationLoadBalancer(cloud, fi.StringValue(e.Name))
+	if err != nil {
+		return nil, err
+	}
+	if lb == nil {
+		return nil, nil
+	}
+
+	actual := &ApplicationLoadBalancer{}
+	actual.Name = e.Name
+	actual.ARN = lb.LoadBalancerArn
+	actual.DNSName = lb.DNSName
+	actual.HostedZoneId = lb.CanonicalHostedZoneId
+	for _, az := range lb.AvailabilityZones {
+		actual.Subnets = append(actual.Subnets, &Subnet{ID: az.SubnetId})
+	}
+	for _, sg := range lb.SecurityGroups {
+		actual.SecurityGroups = append(actual.SecurityGroups, &SecurityGroup{ID: sg})
+	}
+
+	actual.Listeners = make(map[string]*ApplicationLoadBalancerListener)
+
+	listeners, err := cloud.ELBV2().DescribeListeners(&elbv2.DescribeListenersInput{
+		LoadBalancerArn: lb.LoadBalancerArn,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing ALB listeners: %v", err)
+	}
+
+	for _, l := range listeners.Listeners {
+		port := strconv.FormatInt(aws.Int64Value(l.Port), 10)
+		actualListener := &ApplicationLoadBalancerListener{
+			ARN:      l.ListenerArn,
+			Protocol: l.Protocol,
+		}
+		if len(l.Certificates) > 0 {
+			actualListener.SSLCertificateID = l.Certificates[0].CertificateArn
+		}
+
+		rules, err := cloud.ELBV2().DescribeRules(&elbv2.DescribeRulesInput{
+			ListenerArn: l.ListenerArn,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing ALB listener rules: %v", err)
+		}
+		for _, r := range rules.Rules {
+			if aws.BoolValue(r.IsDefault) {
+				continue
+			}
+			rule := &ApplicationLoadBalancerRule{ARN: r.RuleArn}
+			if r.Priority != nil && *r.Priority != "default" {
+				priority, err := strconv.ParseInt(*r.Priority, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing ALB listener rule priority %q: %v", *r.Priority, err)
+				}
+				rule.Priority = aws.Int64(priority)
+			}
+			for _, cond := range r.Conditions {
+				switch aws.StringValue(cond.Field) {
+				case "host-header":
+					if len(cond.Values) > 0 {
+						rule.Host = cond.Values[0]
+					}
+				case "path-pattern":
+					if len(cond.Values) > 0 {
+						rule.Path = cond.Values[0]
+					}
+				}
+			}
+			for _, action := range r.Actions {
+				if aws.StringValue(action.Type) == elbv2.ActionTypeEnumForward {
+					rule.TargetGroup = &TargetGroup{ARN: action.TargetGroupArn}
+				}
+			}
+			actualListener.Rules = append(actualListener.Rules, rule)
+		}
+
+		// Keep rules in priority order, so a diff against the desired
+		// (also priority-ordered) rule list reflects real drift rather
+		// than map/API iteration order.
+		sort.Slice(actualListener.Rules, func(i, j int) bool {
+			return aws.Int64Value(actualListener.Rules[i].Priority) < aws.Int64Value(actualListener.Rules[j].Priority)
+		})
+
+		actual.Listeners[port] = actualListener
+	}
+
+	if e.ARN == nil {
+		e.ARN = actual.ARN
+	}
+
+	return actual, nil
+}
+
+func (e *ApplicationLoadBalancer) Run(c *fi.Context) error {
+	return fi.DefaultDeltaRunMethod(e, c)
+}
+
+func (_ *ApplicationLoadBalancer) CheckChanges(a, e, changes *ApplicationLoadBalancer) error {
+	if a == nil {
+		if fi.StringValue(e.Name) == "" {
+			return fi.RequiredField("Name")
+		}
+		if len(e.Subnets) == 0 {
+			return fi.RequiredField("Subnets")
+		}
+	}
+	return nil
+}
+
+func (_ *ApplicationLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *ApplicationLoadBalancer) error {
+	if a == nil {
+		request := &elbv2.CreateLoadBalancerInput{
+			Name: e.Name,
+			Type: aws.String(elbv2.LoadBalancerTypeEnumApplication),
+		}
+		for _, subnet := range e.Subnets {
+			request.Subnets = append(request.Subnets, subnet.ID)
+		}
+		for _, sg := range e.SecurityGroups {
+			request.SecurityGroups = append(request.SecurityGroups, sg.ID)
+		}
+
+		glog.V(2).Infof("Creating ALB with Name:%q", *e.Name)
+
+		response, err := t.Cloud.ELBV2().CreateLoadBalancer(request)
+		if err != nil {
+			return fmt.Errorf("error creating ALB: %v", err)
+		}
+
+		alb := response.LoadBalancers[0]
+		e.ARN = alb.LoadBalancerArn
+		e.DNSName = alb.DNSName
+		e.HostedZoneId = alb.CanonicalHostedZoneId
+
+		for loadBalancerPort, listener := range e.Listeners {
+			if err := createALBListener(t.Cloud, alb.LoadBalancerArn, loadBalancerPort, listener); err != nil {
+				return err
+			}
+		}
+	} else {
+		if changes.Subnets != nil {
+			return fmt.Errorf("subnet changes on ApplicationLoadBalancer not yet implemented")
+		}
+
+		for loadBalancerPort, listener := range changes.Listeners {
+			actualListener := a.Listeners[loadBalancerPort]
+			if actualListener == nil {
+				if err := createALBListener(t.Cloud, a.ARN, loadBalancerPort, listener); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := reconcileALBListener(t.Cloud, actualListener, listener); err != nil {
+				return err
+			}
+		}
+	}
+
+	return t.AddELBV2Tags(*e.ARN, t.Cloud.BuildTags(e.Name))
+}
+
+func createALBListener(cloud awsup.AWSCloud, lbARN *string, loadBalancerPort string, listener *ApplicationLoadBalancerListener) error {
+	loadBalancerPortInt, err := strconv.ParseInt(loadBalancerPort, 10, 64)
+	if err != nil {
+		return fmt.Errorf("error parsing load balancer listener port: %q", loadBalancerPort)
+	}
+
+	protocol := fi.StringValue(listener.Protocol)
+	if protocol == "" {
+		protocol = elbv2.ProtocolEnumHttp
+	}
+
+	request := &elbv2.CreateListenerInput{
+		LoadBalancerArn: lbARN,
+		Port:            aws.Int64(loadBalancerPortInt),
+		Protocol:        aws.String(protocol),
+		DefaultActions: []*elbv2.Action{
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				TargetGroupArn: listener.DefaultTargetGroup.ARN,
+			},
+		},
+	}
+	if listener.SSLCertificateID != nil {
+		request.Certificates = []*elbv2.Certificate{
+			{CertificateArn: listener.SSLCertificateID},
+		}
+	}
+
+	glog.V(2).Infof("Creating ALB listener on port %d", loadBalancerPortInt)
+
+	response, err := cloud.ELBV2().CreateListener(request)
+	if err != nil {
+		return fmt.Errorf("error creating ALB listener on port %d: %v", loadBalancerPortInt, err)
+	}
+	listenerARN := response.Listeners[0].ListenerArn
+
+	// Rules are evaluated in priority order; sort so lower priorities (which
+	// elbv2 requires to be unique and evaluates first) are created first.
+	rules := append([]*ApplicationLoadBalancerRule{}, listener.Rules...)
+	sort.Slice(rules, func(i, j int) bool {
+		return aws.Int64Value(rules[i].Priority) < aws.Int64Value(rules[j].Priority)
+	})
+
+	for _, rule := range rules {
+		if err := createALBListenerRule(cloud, listenerARN, rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createALBListenerRule(cloud awsup.AWSCloud, listenerARN *string, rule *ApplicationLoadBalancerRule) error {
+	var conditions []*elbv2.RuleCondition
+	if rule.Host != nil {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field:  aws.String("host-header"),
+			Values: []*string{rule.Host},
+		})
+	}
+	if rule.Path != nil {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field:  aws.String("path-pattern"),
+			Values: []*string{rule.Path},
+		})
+	}
+
+	_, err := cloud.ELBV2().CreateRule(&elbv2.CreateRuleInput{
+		ListenerArn: listenerARN,
+		Priority:    rule.Priority,
+		Conditions:  conditions,
+		Actions: []*elbv2.Action{
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				TargetGroupArn: rule.TargetGroup.ARN,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating ALB listener rule (priority %d): %v", aws.Int64Value(rule.Priority), err)
+	}
+
+	return nil
+}
+
+// reconcileALBListener brings an already-created elbv2 listener in line with
+// the desired listener. It applies the listener's own properties (protocol,
+// certificate, default action) with ModifyListener, then reconciles its
+// rules by priority -- which is the part Find's drift detection already
+// diffs, but had nothing to apply it with.
+func reconcileALBListener(cloud awsup.AWSCloud, actual *ApplicationLoadBalancerListener, desired *ApplicationLoadBalancerListener) error {
+	protocol := fi.StringValue(desired.Protocol)
+	if protocol == "" {
+		protocol = elbv2.ProtocolEnumHttp
+	}
+
+	request := &elbv2.ModifyListenerInput{
+		ListenerArn: actual.ARN,
+		Protocol:    aws.String(protocol),
+		DefaultActions: []*elbv2.Action{
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				TargetGroupArn: desired.DefaultTargetGroup.ARN,
+			},
+		},
+	}
+	if desired.SSLCertificateID != nil {
+		request.Certificates = []*elbv2.Certificate{
+			{CertificateArn: desired.SSLCertificateID},
+		}
+	}
+
+	glog.V(2).Infof("Updating ALB listener %q", aws.StringValue(actual.ARN))
+
+	if _, err := cloud.ELBV2().ModifyListener(request); err != nil {
+		return fmt.Errorf("error updating ALB listener %q: %v", aws.StringValue(actual.ARN), err)
+	}
+
+	return reconcileALBListenerRules(cloud, actual.ARN, actual.Rules, desired.Rules)
+}
+
+// reconcileALBListenerRules diffs actual against desired by priority (rule
+// identity on an elbv2 listener), issuing CreateRule for priorities only in
+// desired, ModifyRule for priorities present in both but changed, and
+// DeleteRule for priorities only in actual.
+func reconcileALBListenerRules(cloud awsup.AWSCloud, listenerARN *string, actual []*ApplicationLoadBalancerRule, desired []*ApplicationLoadBalancerRule) error {
+	actualByPriority := make(map[int64]*ApplicationLoadBalancerRule)
+	for _, rule := range actual {
+		actualByPriority[aws.Int64Value(rule.Priority)] = rule
+	}
+
+	desiredPriorities := make(map[int64]bool)
+	for _, rule := range desired {
+		priority := aws.Int64Value(rule.Priority)
+		desiredPriorities[priority] = true
+
+		existing, found := actualByPriority[priority]
+		if !found {
+			if err := createALBListenerRule(cloud, listenerARN, rule); err != nil {
+				return err
+			}
+			continue
+		}
+		if alBListenerRuleEqual(existing, rule) {
+			continue
+		}
+		if err := modifyALBListenerRule(cloud, existing.ARN, rule); err != nil {
+			return err
+		}
+	}
+
+	for priority, existing := range actualByPriority {
+		if desiredPriorities[priority] {
+			continue
+		}
+		if err := deleteALBListenerRule(cloud, existing.ARN); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func alBListenerRuleEqual(a, b *ApplicationLoadBalancerRule) bool {
+	return fi.StringValue(a.Host) == fi.StringValue(b.Host) &&
+		fi.StringValue(a.Path) == fi.StringValue(b.Path) &&
+		fi.StringValue(a.TargetGroup.ARN) == fi.StringValue(b.TargetGroup.ARN)
+}
+
+func modifyALBListenerRule(cloud awsup.AWSCloud, ruleARN *string, rule *ApplicationLoadBalancerRule) error {
+	var conditions []*elbv2.RuleCondition
+	if rule.Host != nil {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field:  aws.String("host-header"),
+			Values: []*string{rule.Host},
+		})
+	}
+	if rule.Path != nil {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field:  aws.String("path-pattern"),
+			Values: []*string{rule.Path},
+		})
+	}
+
+	_, err := cloud.ELBV2().ModifyRule(&elbv2.ModifyRuleInput{
+		RuleArn:    ruleARN,
+		Conditions: conditions,
+		Actions: []*elbv2.Action{
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				TargetGroupArn: rule.TargetGroup.ARN,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating ALB listener rule (priority %d): %v", aws.Int64Value(rule.Priority), err)
+	}
+
+	return nil
+}
+
+func deleteALBListenerRule(cloud awsup.AWSCloud, ruleARN *string) error {
+	_, err := cloud.ELBV2().DeleteRule(&elbv2.DeleteRuleInput{
+		RuleArn: ruleARN,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting ALB listener rule: %v", err)
+	}
+
+	return nil
+}
+
+type terraformALB struct {
+	Name             string               `json:"name,omitempty"`
+	LoadBalancerType string               `json:"load_balancer_type,omitempty"`
+	Subnets          []*terraform.Literal `json:"subnets,omitempty"`
+	SecurityGroups   []*terraform.Literal `json:"security_groups,omitempty"`
+}
+
+type terraformALBListener struct {
+	LoadBalancerARN *terraform.Literal           `json:"load_balancer_arn,omitempty"`
+	Port            int64                        `json:"port,omitempty"`
+	Protocol        string                       `json:"protocol,omitempty"`
+	CertificateARN  string                       `json:"certificate_arn,omitempty"`
+	DefaultAction   []terraformALBListenerAction `json:"default_action,omitempty"`
+}
+
+type terraformALBListenerAction struct {
+	Type           string             `json:"type,omitempty"`
+	TargetGroupARN *terraform.Literal `json:"target_group_arn,omitempty"`
+}
+
+type terraformALBListenerRule struct {
+	ListenerARN *terraform.Literal                  `json:"listener_arn,omitempty"`
+	Priority    int64                               `json:"priority,omitempty"`
+	Action      []terraformALBListenerAction        `json:"action,omitempty"`
+	Condition   []terraformALBListenerRuleCondition `json:"condition,omitempty"`
+}
+
+type terraformALBListenerRuleCondition struct {
+	Field  string   `json:"field,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+func (e *ApplicationLoadBalancer) TerraformLink() *terraform.Literal {
+	return terraform.LiteralProperty("aws_lb", *e.Name, "arn")
+}
+
+func (_ *ApplicationLoadBalancer) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *ApplicationLoadBalancer) error {
+	tf := &terraformALB{
+		Name:             *e.Name,
+		LoadBalancerType: "application",
+	}
+	tf.Subnets = make([]*terraform.Literal, len(e.Subnets))
+	for idx, subnet := range e.Subnets {
+		tf.Subnets[idx] = subnet.TerraformLink()
+	}
+	tf.SecurityGroups = make([]*terraform.Literal, len(e.SecurityGroups))
+	for idx, group := range e.SecurityGroups {
+		tf.SecurityGroups[idx] = group.TerraformLink()
+	}
+
+	if err := t.RenderResource("aws_lb", *e.Name, tf); err != nil {
+		return err
+	}
+
+	for loadBalancerPort, listener := range e.Listeners {
+		loadBalancerPortInt, err := strconv.ParseInt(loadBalancerPort, 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing load balancer listener port: %q", loadBalancerPort)
+		}
+
+		protocol := fi.StringValue(listener.Protocol)
+		if protocol == "" {
+			protocol = elbv2.ProtocolEnumHttp
+		}
+
+		listenerName := *e.Name + "-" + loadBalancerPort
+		tfListener := &terraformALBListener{
+			LoadBalancerARN: e.TerraformLink(),
+			Port:            loadBalancerPortInt,
+			Protocol:        protocol,
+			CertificateARN:  fi.StringValue(listener.SSLCertificateID),
+			DefaultAction: []terraformALBListenerAction{
+				{
+					Type:           "forward",
+					TargetGroupARN: listener.DefaultTargetGroup.TerraformLink(),
+				},
+			},
+		}
+		if err := t.RenderResource("aws_lb_listener", listenerName, tfListener); err != nil {
+			return err
+		}
+
+		for _, rule := range listener.Rules {
+			var conditions []terraformALBListenerRuleCondition
+			if rule.Host != nil {
+				conditions = append(conditions, terraformALBListenerRuleCondition{Field: "host-header", Values: []string{*rule.Host}})
+			}
+			if rule.Path != nil {
+				conditions = append(conditions, terraformALBListenerRuleCondition{Field: "path-pattern", Values: []string{*rule.Path}})
+			}
+
+			ruleName := fmt.Sprintf("%s-%s-%d", *e.Name, loadBalancerPort, fi.Int64Value(rule.Priority))
+			tfRule := &terraformALBListenerRule{
+				ListenerARN: terraform.LiteralProperty("aws_lb_listener", listenerName, "arn"),
+				Priority:    fi.Int64Value(rule.Priority),
+				Action: []terraformALBListenerAction{
+					{
+						Type:           "forward",
+						TargetGroupARN: rule.TargetGroup.TerraformLink(),
+					},
+				},
+				Condition: conditions,
+			}
+			if err := t.RenderResource("aws_lb_listener_rule", ruleName, tfRule); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/golang/glog"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
+)
+
+// TargetGroup is an elbv2 target group, used by NLB/ALB backed LoadBalancer
+// and ApplicationLoadBalancer tasks.
+//
+// TODO: nothing in this tree yet registers instances into the group (e.g. an
+// AutoscalingGroup task setting TargetGroupARNs and calling RegisterTargets),
+// so a target group created by this task starts out empty and stays that
+// way until that wiring exists.
+//go:generate fitask -type=TargetGroup
+type TargetGroup struct {
+	Name *string
+
+	// ARN is the target group's ARN, assigned by AWS on creation
+	ARN *string
+
+	VPC      *VPC
+	Port     *int64
+	Protocol *string
+
+	HealthCheck *TargetGroupHealthCheck
+
+	// Stickiness configures session affinity, used by ApplicationLoadBalancer
+	// target groups; it is meaningless for the TCP target groups used by NLBs.
+	Stickiness *TargetGroupStickiness
+}
+
+// TargetGroupStickiness configures the lb_cookie stickiness attribute of an
+// ALB target group.
+type TargetGroupStickiness struct {
+	Enabled  *bool
+	Duration *int64
+}
+
+type TargetGroupHealthCheck struct {
+	Path     *string
+	Port     *int64
+	Protocol *string
+	Interval *int64
+	Timeout  *int64
+	// Matcher is the range of acceptable HTTP response codes (eg "200" or
+	// "200-299"), mapped to elbv2's Matcher.HttpCode. Only meaningful for
+	// HTTP/HTTPS health checks; AWS defaults to "200" when unset.
+	Matcher            *string
+	HealthyThreshold   *int64
+	UnhealthyThreshold *int64
+}
+
+var _ fi.CompareWithID = &TargetGroup{}
+
+func (e *TargetGroup) CompareWithID() *string {
+	return e.ARN
+}
+
+func findTargetGroup(cloud awsup.AWSCloud, name string) (*elbv2.TargetGroup, error) {
+	request := &elbv2.DescribeTargetGroupsInput{
+		Names: []*string{&name},
+	}
+
+	var found []*elbv2.TargetGroup
+	err := cloud.ELBV2().DescribeTargetGroupsPages(request, func(p *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+		for _, tg := range p.TargetGroups {
+			if aws.StringValue(tg.TargetGroupName) == name {
+				found = append(found, tg)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		if awsError, ok := err.(awserr.Error); ok {
+			if awsError.Code() == elbv2.ErrCodeTargetGroupNotFoundException {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("error listing target groups: %v", err)
+	}
+
+	if len(found) == 0 {
+		return nil, nil
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("found multiple TargetGroups with name %q", name)
+	}
+	return found[0], nil
+}
+
+func (e *TargetGroup) Find(c *fi.Context) (*TargetGroup, error) {
+	cloud := c.Cloud.(awsup.AWSCloud)
+
+	tg, err := findTargetGroup(cloud, fi.StringValue(e.Name))
+	if err != nil {
+		return nil, err
+	}
+	if tg == nil {
+		return nil, nil
+	}
+
+	actual := &TargetGroup{}
+	actual.Name = e.Name
+	actual.ARN = tg.TargetGroupArn
+	actual.VPC = &VPC{ID: tg.VpcId}
+	actual.Port = tg.Port
+	actual.Protocol = tg.Protocol
+
+	if tg.HealthCheckPath != nil {
+		actual.HealthCheck = &TargetGroupHealthCheck{
+			Path:               tg.HealthCheckPath,
+			Port:               intFromStringPort(tg.HealthCheckPort),
+			Protocol:           tg.HealthCheckProtocol,
+			Interval:           tg.HealthCheckIntervalSeconds,
+			Timeout:            tg.HealthCheckTimeoutSeconds,
+			HealthyThreshold:   tg.HealthyThresholdCount,
+			UnhealthyThreshold: tg.UnhealthyThresholdCount,
+		}
+		if tg.Matcher != nil {
+			actual.HealthCheck.Matcher = tg.Matcher.HttpCode
+		}
+	}
+
+	if e.ARN == nil {
+		e.ARN = actual.ARN
+	}
+
+	return actual, nil
+}
+
+// intFromStringPort parses the elbv2 HealthCheckPort, which is a string such
+// as "traffic-port" or "8080", into an *int64. A non-numeric port (eg the
+// "traffic-port" default) is reported as nil.
+func intFromStringPort(port *string) *int64 {
+	if port == nil {
+		return nil
+	}
+	var v int64
+	if _, err := fmt.Sscanf(*port, "%d", &v); err != nil {
+		return nil
+	}
+	return &v
+}
+
+func (e *TargetGroup) Run(c *fi.Context) error {
+	return fi.DefaultDeltaRunMethod(e, c)
+}
+
+func (_ *TargetGroup) CheckChanges(a, e, changes *TargetGroup) error {
+	if a == nil {
+		if fi.StringValue(e.Name) == "" {
+			return fi.RequiredField("Name")
+		}
+		if e.VPC == nil {
+			return fi.RequiredField("VPC")
+		}
+		if e.Port == nil {
+			return fi.RequiredField("Port")
+		}
+	}
+	if a != nil {
+		if changes.VPC != nil {
+			return fi.CannotChangeField("VPC")
+		}
+		if changes.Protocol != nil {
+			return fi.CannotChangeField("Protocol")
+		}
+	}
+	return nil
+}
+
+func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGroup) error {
+	if a == nil {
+		request := &elbv2.CreateTargetGroupInput{
+			Name:     e.Name,
+			VpcId:    e.VPC.ID,
+			Port:     e.Port,
+			Protocol: e.Protocol,
+		}
+
+		if e.HealthCheck != nil {
+			request.HealthCheckPath = e.HealthCheck.Path
+			request.HealthCheckIntervalSeconds = e.HealthCheck.Interval
+			request.HealthCheckTimeoutSeconds = e.HealthCheck.Timeout
+			request.HealthyThresholdCount = e.HealthCheck.HealthyThreshold
+			request.UnhealthyThresholdCount = e.HealthCheck.UnhealthyThreshold
+			if e.HealthCheck.Protocol != nil {
+				request.HealthCheckProtocol = e.HealthCheck.Protocol
+			}
+			if e.HealthCheck.Matcher != nil {
+				request.Matcher = &elbv2.Matcher{HttpCode: e.HealthCheck.Matcher}
+			}
+		}
+
+		glog.V(2).Infof("Creating TargetGroup with Name:%q", *e.Name)
+
+		response, err := t.Cloud.ELBV2().CreateTargetGroup(request)
+		if err != nil {
+			return fmt.Errorf("error creating TargetGroup: %v", err)
+		}
+
+		e.ARN = response.TargetGroups[0].TargetGroupArn
+
+		if e.Stickiness != nil {
+			if err := modifyTargetGroupStickiness(t.Cloud, e); err != nil {
+				return err
+			}
+		}
+	} else {
+		if changes.Stickiness != nil {
+			if err := modifyTargetGroupStickiness(t.Cloud, e); err != nil {
+				return err
+			}
+		}
+		if changes.HealthCheck != nil {
+			if err := modifyTargetGroupHealthCheck(t.Cloud, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func modifyTargetGroupHealthCheck(cloud awsup.AWSCloud, e *TargetGroup) error {
+	request := &elbv2.ModifyTargetGroupInput{
+		TargetGroupArn: e.ARN,
+	}
+	if e.HealthCheck.Path != nil {
+		request.HealthCheckPath = e.HealthCheck.Path
+	}
+	if e.HealthCheck.Interval != nil {
+		request.HealthCheckIntervalSeconds = e.HealthCheck.Interval
+	}
+	if e.HealthCheck.Timeout != nil {
+		request.HealthCheckTimeoutSeconds = e.HealthCheck.Timeout
+	}
+	if e.HealthCheck.HealthyThreshold != nil {
+		request.HealthyThresholdCount = e.HealthCheck.HealthyThreshold
+	}
+	if e.HealthCheck.UnhealthyThreshold != nil {
+		request.UnhealthyThresholdCount = e.HealthCheck.UnhealthyThreshold
+	}
+	if e.HealthCheck.Matcher != nil {
+		request.Matcher = &elbv2.Matcher{HttpCode: e.HealthCheck.Matcher}
+	}
+
+	glog.V(2).Infof("Updating TargetGroup health check for %q", *e.Name)
+
+	if _, err := cloud.ELBV2().ModifyTargetGroup(request); err != nil {
+		return fmt.Errorf("error updating TargetGroup health check: %v", err)
+	}
+
+	return nil
+}
+
+// modifyTargetGroupStickiness sets the ALB target group's lb_cookie
+// stickiness attributes. Stickiness is an attribute on the target group
+// itself, not a field of CreateTargetGroupInput, so it is always applied as
+// a follow-up ModifyTargetGroupAttributes call.
+func modifyTargetGroupStickiness(cloud awsup.AWSCloud, e *TargetGroup) error {
+	attrs := []*elbv2.TargetGroupAttribute{
+		{
+			Key:   aws.String("stickiness.enabled"),
+			Value: aws.String(fmt.Sprintf("%t", fi.BoolValue(e.Stickiness.Enabled))),
+		},
+		{
+			Key:   aws.String("stickiness.type"),
+			Value: aws.String("lb_cookie"),
+		},
+	}
+	if e.Stickiness.Duration != nil {
+		attrs = append(attrs, &elbv2.TargetGroupAttribute{
+			Key:   aws.String("stickiness.lb_cookie.duration_seconds"),
+			Value: aws.String(fmt.Sprintf("%d", fi.Int64Value(e.Stickiness.Duration))),
+		})
+	}
+
+	_, err := cloud.ELBV2().ModifyTargetGroupAttributes(&elbv2.ModifyTargetGroupAttributesInput{
+		TargetGroupArn: e.ARN,
+		Attributes:     attrs,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting TargetGroup stickiness: %v", err)
+	}
+
+	return nil
+}
+
+type terraformTargetGroup struct {
+	Name        string                      `json:"name,omitempty"`
+	VPCID       *terraform.Literal          `json:"vpc_id,omitempty"`
+	Port        int64                       `json:"port,omitempty"`
+	Protocol    string                      `json:"protocol,omitempty"`
+	HealthCheck *terraformTargetHealthCheck `json:"health_check,omitempty"`
+	Stickiness  *terraformTargetStickiness  `json:"stickiness,omitempty"`
+}
+
+type terraformTargetHealthCheck struct {
+	Path               string `json:"path,omitempty"`
+	Interval           int64  `json:"interval,omitempty"`
+	Timeout            int64  `json:"timeout,omitempty"`
+	Matcher            string `json:"matcher,omitempty"`
+	HealthyThreshold   int64  `json:"healthy_threshold,omitempty"`
+	UnhealthyThreshold int64  `json:"unhealthy_threshold,omitempty"`
+}
+
+type terraformTargetStickiness struct {
+	Enabled bool   `json:"enabled"`
+	Type    string `json:"type,omitempty"`
+	Duration int64 `json:"cookie_duration,omitempty"`
+}
+
+func (_ *TargetGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *TargetGroup) error {
+	tf := &terraformTargetGroup{
+		Name:     *e.Name,
+		VPCID:    e.VPC.TerraformLink(),
+		Port:     *e.Port,
+		Protocol: *e.Protocol,
+	}
+
+	if e.HealthCheck != nil {
+		tf.HealthCheck = &terraformTargetHealthCheck{
+			Path:               fi.StringValue(e.HealthCheck.Path),
+			Interval:           fi.Int64Value(e.HealthCheck.Interval),
+			Timeout:            fi.Int64Value(e.HealthCheck.Timeout),
+			Matcher:            fi.StringValue(e.HealthCheck.Matcher),
+			HealthyThreshold:   fi.Int64Value(e.HealthCheck.HealthyThreshold),
+			UnhealthyThreshold: fi.Int64Value(e.HealthCheck.UnhealthyThreshold),
+		}
+	}
+
+	if e.Stickiness != nil {
+		tf.Stickiness = &terraformTargetStickiness{
+			Enabled:  fi.BoolValue(e.Stickiness.Enabled),
+			Type:     "lb_cookie",
+			Duration: fi.Int64Value(e.Stickiness.Duration),
+		}
+	}
+
+	return t.RenderResource("aws_lb_target_group", *e.Name, tf)
+}
+
+func (e *TargetGroup) TerraformLink() *terraform.Literal {
+	return terraform.LiteralProperty("aws_lb_target_group", *e.Name, "arn")
+}